@@ -14,11 +14,13 @@
 package bindinfo_test
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/parser"
@@ -587,3 +589,64 @@ func (s *testSuite) TestBindingCache(c *C) {
 	c.Assert(s.domain.BindHandle().Update(false), IsNil)
 	c.Assert(len(s.domain.BindHandle().GetAllBindRecord()), Equals, 1)
 }
+
+// TestRegressionDetection injects latency deltas via BindHandle.RecordExecution
+// directly, analogous to TestAddEvolveTasks, and asserts that a drifting query
+// is demoted to "pending verify" and queued for re-evolution.
+func (s *testSuite) TestRegressionDetection(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	s.cleanBindingEnv(tk)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a int, index idx_a(a))")
+	tk.MustExec("create global binding for select * from t where a > 1 using select * from t use index(idx_a) where a > 1")
+
+	sql, hash := parser.NormalizeDigest("select * from t where a > 1")
+	bindHandle := s.domain.BindHandle()
+
+	for i := 0; i < 10; i++ {
+		bindHandle.RecordExecution("test", sql, hash, int64(10*time.Millisecond))
+	}
+	c.Assert(len(bindHandle.GetEvolveTasks()), Equals, 0)
+
+	for i := 0; i < 10; i++ {
+		bindHandle.RecordExecution("test", sql, hash, int64(100*time.Millisecond))
+	}
+
+	tasks := bindHandle.GetEvolveTasks()
+	c.Assert(len(tasks), Equals, 1)
+	c.Assert(tasks[0].SQLDigest, Equals, hash)
+
+	bindData := bindHandle.GetBindRecord(hash, sql, "test")
+	c.Assert(bindData, NotNil)
+	c.Assert(bindData.Bindings[0].Status, Equals, bindinfo.PendingVerify)
+}
+
+// TestExportImport checks that a BindHandle's bindings round-trip through
+// Export/Import into another handle on the same storage.
+func (s *testSuite) TestExportImport(c *C) {
+	tk := testkit.NewTestKit(c, s.store)
+	s.cleanBindingEnv(tk)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a int, index idx_a(a))")
+	tk.MustExec("create global binding for select * from t where a > 1 using select * from t use index(idx_a) where a > 1")
+	tk.MustExec("create global binding for select * from t where a > 2 using select * from t use index(idx_a) where a > 2")
+
+	source := s.domain.BindHandle()
+	var buf bytes.Buffer
+	c.Assert(source.Export(&buf), IsNil)
+
+	target := bindinfo.NewBindHandle(tk.Se)
+	c.Assert(target.Import(bytes.NewReader(buf.Bytes()), false), IsNil)
+	c.Assert(target.Update(true), IsNil)
+
+	c.Assert(target.Size(), Equals, source.Size())
+	for _, record := range source.GetAllBindRecord() {
+		hash := parser.DigestNormalized(record.OriginalSQL)
+		imported := target.GetBindRecord(hash, record.OriginalSQL, record.Db)
+		c.Assert(imported, NotNil)
+		c.Assert(len(imported.Bindings), Equals, len(record.Bindings))
+		c.Assert(imported.Bindings[0].BindSQL, Equals, record.Bindings[0].BindSQL)
+	}
+}
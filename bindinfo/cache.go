@@ -0,0 +1,213 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"time"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/tidb/types"
+)
+
+// Status of a Binding.
+const (
+	// Using indicates the binding is in use.
+	Using = "using"
+	// deleted indicates the binding has been deleted, but the cache or disk
+	// doesn't have time to delete it.
+	deleted = "deleted"
+	// invalid indicates that the binding's bound table or index no longer
+	// exists in the current schema and can not be used anymore.
+	invalid = "invalid"
+	// PendingVerify means the binding needs to be verified.
+	PendingVerify = "pending verify"
+	// Rejected means that the binding is rejected after verification.
+	Rejected = "rejected"
+)
+
+// Binding stores the basic bind hint info.
+type Binding struct {
+	BindSQL string
+	// Status represents the status of the binding. It can be one of "using",
+	// "deleted", "invalid", "pending verify" and "rejected".
+	Status     string
+	CreateTime types.Time
+	UpdateTime types.Time
+	Charset    string
+	Collation  string
+	// Tables stores the schema-qualified tables ("db.tbl") referenced by
+	// BindSQL, gathered by walking the parsed AST when the binding is
+	// created and persisted in the `tables` column of mysql.bind_info. It
+	// lets the cache answer "which bindings touch table X" without
+	// re-parsing BindSQL. Rows written before that column existed load with
+	// it empty, so the loader falls back to re-deriving it from BindSQL.
+	Tables []string
+}
+
+func (b *Binding) isSame(rb *Binding) bool {
+	return b.BindSQL == rb.BindSQL
+}
+
+// clone returns a deep copy of the binding, used when building a new
+// BindRecord so mutations to the copy don't leak back into the cache.
+func (b *Binding) clone() Binding {
+	nb := *b
+	nb.Tables = append([]string(nil), b.Tables...)
+	return nb
+}
+
+// BindRecord represents a sql bind record retrieved from the storage.
+type BindRecord struct {
+	OriginalSQL string
+	Db          string
+
+	Bindings []Binding
+}
+
+// Tables returns the union of the tables referenced by every live Binding in
+// this record. A record whose bindings reference no tables yet (e.g. loaded
+// before the tables column existed) returns an empty slice.
+func (br *BindRecord) Tables() []string {
+	seen := make(map[string]struct{})
+	tables := make([]string, 0, 4)
+	for _, b := range br.Bindings {
+		for _, t := range b.Tables {
+			if _, ok := seen[t]; ok {
+				continue
+			}
+			seen[t] = struct{}{}
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// HasUsingBinding checks if there are any using bindings in bind record.
+func (br *BindRecord) HasUsingBinding() bool {
+	for _, binding := range br.Bindings {
+		if binding.Status == Using {
+			return true
+		}
+	}
+	return false
+}
+
+// FindBinding find bindings in BindRecord.
+func (br *BindRecord) FindBinding(bindSQL string) *Binding {
+	for i, binding := range br.Bindings {
+		if binding.BindSQL == bindSQL {
+			return &br.Bindings[i]
+		}
+	}
+	return nil
+}
+
+func (br *BindRecord) isSame(other *BindRecord) bool {
+	return br.OriginalSQL == other.OriginalSQL && br.Db == other.Db
+}
+
+// copy copies a new BindRecord.
+func (br *BindRecord) copy() *BindRecord {
+	nbr := &BindRecord{
+		OriginalSQL: br.OriginalSQL,
+		Db:          br.Db,
+		Bindings:    make([]Binding, 0, len(br.Bindings)),
+	}
+	for _, b := range br.Bindings {
+		nbr.Bindings = append(nbr.Bindings, b.clone())
+	}
+	return nbr
+}
+
+// tableNamesFromNode walks the parsed AST of a bound statement and collects
+// the schema-qualified table names ("db.tbl") it references, lower-cased and
+// de-duplicated. defaultDB fills in unqualified table refs.
+func tableNamesFromNode(node ast.Node, defaultDB string) []string {
+	v := &tableNameVisitor{defaultDB: defaultDB, seen: map[string]struct{}{}}
+	node.Accept(v)
+	return v.tables
+}
+
+type tableNameVisitor struct {
+	defaultDB string
+	seen      map[string]struct{}
+	tables    []string
+}
+
+// Enter implements ast.Visitor.
+func (v *tableNameVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		db := tn.Schema.L
+		if db == "" {
+			db = v.defaultDB
+		}
+		name := db + "." + tn.Name.L
+		if _, ok := v.seen[name]; !ok {
+			v.seen[name] = struct{}{}
+			v.tables = append(v.tables, name)
+		}
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor.
+func (v *tableNameVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// tableIndexRef identifies a single index referenced by a USE/FORCE/IGNORE
+// INDEX hint: the schema-qualified table it hints and the index name.
+type tableIndexRef struct {
+	db, tbl, idx string
+}
+
+// indexHintsFromNode walks the parsed AST of a bound statement and collects
+// every index referenced via USE/FORCE/IGNORE INDEX hints. It lets
+// DropInvalidBindRecord tell an index-level hint (still valid as long as the
+// table and the index both exist) from one whose hinted index has since
+// been dropped or renamed out from under it.
+func indexHintsFromNode(node ast.Node, defaultDB string) []tableIndexRef {
+	v := &indexHintVisitor{defaultDB: defaultDB}
+	node.Accept(v)
+	return v.refs
+}
+
+type indexHintVisitor struct {
+	defaultDB string
+	refs      []tableIndexRef
+}
+
+// Enter implements ast.Visitor.
+func (v *indexHintVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		db := tn.Schema.L
+		if db == "" {
+			db = v.defaultDB
+		}
+		for _, hint := range tn.IndexHints {
+			for _, idx := range hint.IndexNames {
+				v.refs = append(v.refs, tableIndexRef{db: db, tbl: tn.Name.L, idx: idx.L})
+			}
+		}
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor.
+func (v *indexHintVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// nowTime is a small indirection so tests can stub out the clock if needed.
+var nowTime = func() time.Time { return time.Now() }
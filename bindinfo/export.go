@@ -0,0 +1,113 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/tidb/types"
+)
+
+// exportedBinding is the portable, JSON-stable representation of a Binding.
+// Field names are deliberately spelled out instead of serializing Binding
+// directly so the file format doesn't shift if internal fields are renamed.
+type exportedBinding struct {
+	BindSQL    string `json:"bind_sql"`
+	Status     string `json:"status"`
+	CreateTime string `json:"create_time"`
+	UpdateTime string `json:"update_time"`
+	Charset    string `json:"charset"`
+	Collation  string `json:"collation"`
+}
+
+// exportedBindRecord is the portable representation of a BindRecord.
+type exportedBindRecord struct {
+	OriginalSQL string            `json:"original_sql"`
+	Db          string            `json:"default_db"`
+	Bindings    []exportedBinding `json:"bindings"`
+}
+
+// Export serializes the entire BindHandle cache to a stable JSON format, so
+// it can be migrated to another cluster or checked into version control.
+func (h *BindHandle) Export(w io.Writer) error {
+	records := h.GetAllBindRecord()
+	exported := make([]exportedBindRecord, 0, len(records))
+	for _, record := range records {
+		er := exportedBindRecord{OriginalSQL: record.OriginalSQL, Db: record.Db}
+		for _, b := range record.Bindings {
+			er.Bindings = append(er.Bindings, exportedBinding{
+				BindSQL:    b.BindSQL,
+				Status:     b.Status,
+				CreateTime: b.CreateTime.String(),
+				UpdateTime: b.UpdateTime.String(),
+				Charset:    b.Charset,
+				Collation:  b.Collation,
+			})
+		}
+		exported = append(exported, er)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exported)
+}
+
+// Import reads bindings previously written by Export and adds them via
+// AddBindRecord, so they're persisted to mysql.bind_info and merged into the
+// cache the same way a freshly-created binding would be, preserving the
+// exported create/update times instead of re-stamping NOW(). If overwrite is
+// false, a (original_sql, default_db, bind_sql) combo already present in the
+// cache is skipped instead of being replaced; if true, AddBindRecord's
+// upsert behaviour replaces the existing row in place rather than
+// duplicating it.
+func (h *BindHandle) Import(r io.Reader, overwrite bool) error {
+	var exported []exportedBindRecord
+	if err := json.NewDecoder(r).Decode(&exported); err != nil {
+		return errors.Trace(err)
+	}
+
+	sc := h.sctx.Context.GetSessionVars().StmtCtx
+	for _, er := range exported {
+		existing := h.GetBindRecord(parser.DigestNormalized(er.OriginalSQL), er.OriginalSQL, er.Db)
+		record := &BindRecord{OriginalSQL: er.OriginalSQL, Db: er.Db}
+		for _, eb := range er.Bindings {
+			if !overwrite && existing != nil && existing.FindBinding(eb.BindSQL) != nil {
+				continue
+			}
+			binding := Binding{
+				BindSQL:   eb.BindSQL,
+				Status:    eb.Status,
+				Charset:   eb.Charset,
+				Collation: eb.Collation,
+			}
+			if ct, err := types.ParseTime(sc, eb.CreateTime, mysql.TypeDatetime, types.MaxFsp); err == nil {
+				binding.CreateTime = ct
+			}
+			if ut, err := types.ParseTime(sc, eb.UpdateTime, mysql.TypeDatetime, types.MaxFsp); err == nil {
+				binding.UpdateTime = ut
+			}
+			record.Bindings = append(record.Bindings, binding)
+		}
+		if len(record.Bindings) == 0 {
+			continue
+		}
+		if err := h.AddBindRecord(h.sctx.Context, record); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,652 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/sqlexec"
+)
+
+// BindHandle is used to handle all global sql bind operations.
+type BindHandle struct {
+	sctx struct {
+		sync.Mutex
+		sessionctx.Context
+	}
+
+	// bindInfo caches the binding info retrieved from the storage. Each bind
+	// record is keyed by its normalized-SQL digest; several records with the
+	// same digest but different default DBs can share a bucket.
+	bindInfo struct {
+		sync.Mutex
+		atomic.Value
+	}
+
+	// tableIndex maps a schema-qualified table name ("db.tbl") to the set of
+	// BindRecords that reference it, so GetBindRecordsForTable and
+	// DropBindRecordsForTable don't need to scan the whole cache.
+	tableIndex struct {
+		sync.Mutex
+		atomic.Value // map[string]map[*BindRecord]struct{}
+	}
+
+	// sampler tracks recent execution latencies for queries using a binding,
+	// driving automatic regression detection.
+	sampler *regressionSampler
+
+	// evolveTasks records bindings that regression detection has demoted to
+	// "pending verify" and queued for re-evolution.
+	evolveTasks struct {
+		sync.Mutex
+		tasks []EvolveTask
+	}
+
+	parser *parser.Parser
+}
+
+// Lease influences the duration of loading bind info and handling invalid bind.
+var Lease = 3 * time.Second
+
+// tablesColumnSupported tracks whether mysql.bind_info has been migrated to
+// include the `tables` column this package persists. It starts optimistic
+// and is permanently downgraded the first time a query against that column
+// fails with an unknown-column error, so this package degrades to re-deriving tables from BindSQL
+// against a cluster whose bootstrap hasn't run the migration yet, instead
+// of failing every Update/AddBindRecord outright.
+var tablesColumnSupported int32 = 1
+
+func tablesColumnEnabled() bool {
+	return atomic.LoadInt32(&tablesColumnSupported) != 0
+}
+
+func disableTablesColumn() {
+	atomic.StoreInt32(&tablesColumnSupported, 0)
+}
+
+// isUnknownColumnErr reports whether err looks like mysql.bind_info doesn't
+// have the `tables` column yet.
+func isUnknownColumnErr(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown column")
+}
+
+// NewBindHandle creates a new BindHandle.
+func NewBindHandle(ctx sessionctx.Context) *BindHandle {
+	handle := &BindHandle{parser: parser.New(), sampler: newRegressionSampler()}
+	handle.sctx.Context = ctx
+	handle.bindInfo.Value.Store(make(map[string][]*BindRecord))
+	handle.tableIndex.Value.Store(make(map[string]map[*BindRecord]struct{}))
+	return handle
+}
+
+// bindInfoSelectSQL builds the load query for mysql.bind_info, including
+// the `tables` column only when hasTablesCol is true (see
+// tablesColumnSupported).
+func bindInfoSelectSQL(hasTablesCol, fullLoad bool, lastUpdate string) string {
+	cols := "original_sql, bind_sql, default_db, status, create_time, update_time, charset, collation"
+	if hasTablesCol {
+		cols += ", tables"
+	}
+	sql := "select " + cols + " from mysql.bind_info"
+	if !fullLoad {
+		sql += " where update_time > '" + lastUpdate + "'"
+	}
+	return sql
+}
+
+// Update updates the global sql bind cache.
+func (h *BindHandle) Update(fullLoad bool) (err error) {
+	hasTablesCol := tablesColumnEnabled()
+	lastUpdate := h.lastUpdateTime().Format("2006-01-02 15:04:05.999999")
+	sql := bindInfoSelectSQL(hasTablesCol, fullLoad, lastUpdate)
+
+	h.sctx.Lock()
+	rows, _, err := h.sctx.Context.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(h.sctx.Context, sql)
+	if err != nil && hasTablesCol && isUnknownColumnErr(err) {
+		disableTablesColumn()
+		hasTablesCol = false
+		sql = bindInfoSelectSQL(false, fullLoad, lastUpdate)
+		rows, _, err = h.sctx.Context.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(h.sctx.Context, sql)
+	}
+	h.sctx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	h.bindInfo.Lock()
+	defer h.bindInfo.Unlock()
+	newCache := h.copyBindCache()
+	newTableIndex := h.copyTableIndex()
+	defer func() {
+		h.bindInfo.Value.Store(newCache)
+		h.tableIndex.Value.Store(newTableIndex)
+	}()
+
+	for _, row := range rows {
+		record, err1 := h.newBindRecordFromRow(row, hasTablesCol)
+		if err1 != nil {
+			err = err1
+			continue
+		}
+		oldRecord := h.getBindRecordLocked(newCache, record.OriginalSQL, record.Db)
+		newRecord := merge(oldRecord, record)
+		if len(newRecord.Bindings) > 0 {
+			setBindRecord(newCache, newRecord)
+		} else {
+			removeBindRecord(newCache, newRecord)
+		}
+		reindexBindRecord(newTableIndex, oldRecord, newRecord)
+		updateBindMetrics(oldRecord, newRecord)
+	}
+	return err
+}
+
+func (h *BindHandle) newBindRecordFromRow(row chunk.Row, hasTablesCol bool) (*BindRecord, error) {
+	hint := Binding{
+		BindSQL:    row.GetString(1),
+		Status:     row.GetString(3),
+		CreateTime: row.GetTime(4),
+		UpdateTime: row.GetTime(5),
+		Charset:    row.GetString(6),
+		Collation:  row.GetString(7),
+	}
+	bindRecord := &BindRecord{
+		OriginalSQL: row.GetString(0),
+		Db:          row.GetString(2),
+		Bindings:    []Binding{hint},
+	}
+	if hasTablesCol {
+		if tables := row.GetString(8); tables != "" {
+			bindRecord.Bindings[0].Tables = strings.Split(tables, ",")
+			return bindRecord, nil
+		}
+	}
+	// Rows written before the tables column existed (or a cluster whose
+	// bootstrap hasn't added it yet) load with it empty or absent; fall
+	// back to re-deriving it from BindSQL so they keep working.
+	if stmt, err := h.parser.ParseOneStmt(hint.BindSQL, hint.Charset, hint.Collation); err == nil {
+		bindRecord.Bindings[0].Tables = tableNamesFromNode(stmt, bindRecord.Db)
+	}
+	return bindRecord, nil
+}
+
+// AddBindRecord persists a BindRecord to mysql.bind_info and merges it into
+// the cache, indexing it by the tables referenced in its bind SQL. It is an
+// upsert keyed on (original_sql, default_db, bind_sql): a binding that
+// already exists under that key is UPDATEd in place (e.g. a status
+// transition like drop or regression-demotion), so every caller of
+// AddBindRecord gets row-mutation semantics for free instead of growing a
+// duplicate row per change. sctx is the session the caller wants the
+// persisting SQL executed against.
+func (h *BindHandle) AddBindRecord(sctx sessionctx.Context, record *BindRecord) (err error) {
+	if len(record.Bindings) == 0 {
+		return errors.New("bindinfo: AddBindRecord requires at least one binding")
+	}
+	for i := range record.Bindings {
+		if len(record.Bindings[i].Tables) == 0 {
+			stmt, perr := h.parser.ParseOneStmt(record.Bindings[i].BindSQL, record.Bindings[i].Charset, record.Bindings[i].Collation)
+			if perr == nil {
+				record.Bindings[i].Tables = tableNamesFromNode(stmt, record.Db)
+			}
+		}
+	}
+
+	exec, ok := sctx.(sqlexec.SQLExecutor)
+	if !ok {
+		return errors.New("bindinfo: session context does not support executing SQL")
+	}
+	existing := h.GetBindRecord(parser.DigestNormalized(record.OriginalSQL), record.OriginalSQL, record.Db)
+
+	h.sctx.Lock()
+	for _, b := range record.Bindings {
+		isUpdate := existing != nil && existing.FindBinding(b.BindSQL) != nil
+		hasTablesCol := tablesColumnEnabled()
+		persistSQL := bindInfoPersistSQL(record, b, isUpdate, hasTablesCol)
+		if _, err = exec.Execute(context.Background(), persistSQL); err != nil {
+			if hasTablesCol && isUnknownColumnErr(err) {
+				disableTablesColumn()
+				persistSQL = bindInfoPersistSQL(record, b, isUpdate, false)
+				_, err = exec.Execute(context.Background(), persistSQL)
+			}
+			if err != nil {
+				h.sctx.Unlock()
+				return err
+			}
+		}
+	}
+	h.sctx.Unlock()
+
+	h.bindInfo.Lock()
+	cache := h.copyBindCache()
+	tableIdx := h.copyTableIndex()
+	oldRecord := h.getBindRecordLocked(cache, record.OriginalSQL, record.Db)
+	newRecord := merge(oldRecord, record)
+	if len(newRecord.Bindings) > 0 {
+		setBindRecord(cache, newRecord)
+	} else {
+		removeBindRecord(cache, newRecord)
+	}
+	reindexBindRecord(tableIdx, oldRecord, newRecord)
+	updateBindMetrics(oldRecord, newRecord)
+	h.bindInfo.Value.Store(cache)
+	h.tableIndex.Value.Store(tableIdx)
+	h.bindInfo.Unlock()
+
+	for _, b := range record.Bindings {
+		if b.Status == Using {
+			// A binding entering "using" is a verification event (a fresh
+			// CREATE BINDING, or re-verification after evolution via
+			// CompleteEvolveTask): drop any stale regression baseline and
+			// samples. The next samples re-establish it against this epoch,
+			// unless the caller sets it itself, as CompleteEvolveTask does.
+			h.sampler.resetBaseline(parser.DigestNormalized(record.OriginalSQL))
+			break
+		}
+	}
+	return nil
+}
+
+// bindInfoPersistSQL builds the INSERT/UPDATE statement that persists a
+// single binding of record, updating the existing row in place when
+// isUpdate is set (see AddBindRecord) instead of inserting a duplicate.
+// hasTablesCol selects between the migrated 9-column schema and the
+// original 8-column one (see tablesColumnSupported).
+func bindInfoPersistSQL(record *BindRecord, b Binding, isUpdate, hasTablesCol bool) string {
+	if isUpdate {
+		if hasTablesCol {
+			return fmt.Sprintf(
+				"UPDATE mysql.bind_info SET status=%s, update_time=%s, charset=%s, collation=%s, tables=%s WHERE original_sql=%s AND default_db=%s AND bind_sql=%s",
+				quoteSQL(b.Status), bindTimeExpr(b.UpdateTime), quoteSQL(b.Charset), quoteSQL(b.Collation), quoteSQL(strings.Join(b.Tables, ",")),
+				quoteSQL(record.OriginalSQL), quoteSQL(record.Db), quoteSQL(b.BindSQL))
+		}
+		return fmt.Sprintf(
+			"UPDATE mysql.bind_info SET status=%s, update_time=%s, charset=%s, collation=%s WHERE original_sql=%s AND default_db=%s AND bind_sql=%s",
+			quoteSQL(b.Status), bindTimeExpr(b.UpdateTime), quoteSQL(b.Charset), quoteSQL(b.Collation),
+			quoteSQL(record.OriginalSQL), quoteSQL(record.Db), quoteSQL(b.BindSQL))
+	}
+	if hasTablesCol {
+		return fmt.Sprintf(
+			"INSERT INTO mysql.bind_info(original_sql, bind_sql, default_db, status, create_time, update_time, charset, collation, tables) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)",
+			quoteSQL(record.OriginalSQL), quoteSQL(b.BindSQL), quoteSQL(record.Db), quoteSQL(b.Status),
+			bindTimeExpr(b.CreateTime), bindTimeExpr(b.UpdateTime), quoteSQL(b.Charset), quoteSQL(b.Collation), quoteSQL(strings.Join(b.Tables, ",")))
+	}
+	return fmt.Sprintf(
+		"INSERT INTO mysql.bind_info(original_sql, bind_sql, default_db, status, create_time, update_time, charset, collation) VALUES (%s, %s, %s, %s, %s, %s, %s, %s)",
+		quoteSQL(record.OriginalSQL), quoteSQL(b.BindSQL), quoteSQL(record.Db), quoteSQL(b.Status),
+		bindTimeExpr(b.CreateTime), bindTimeExpr(b.UpdateTime), quoteSQL(b.Charset), quoteSQL(b.Collation))
+}
+
+// bindTimeExpr renders a create/update time for a persisting SQL statement:
+// an explicitly-set time (e.g. one preserved by Import) is quoted verbatim,
+// otherwise it's left to the server's clock via NOW().
+func bindTimeExpr(t types.Time) string {
+	if t.Compare(types.ZeroTime) == 0 {
+		return "NOW()"
+	}
+	return quoteSQL(t.String())
+}
+
+// GetBindRecord returns the BindRecord of the given hash and normalized sql.
+func (h *BindHandle) GetBindRecord(hash, normdOrigSQL, db string) *BindRecord {
+	cache := h.bindInfo.Value.Load().(map[string][]*BindRecord)
+	bindRecords := cache[hash]
+	for _, bindRecord := range bindRecords {
+		if bindRecord.OriginalSQL == normdOrigSQL && (bindRecord.Db == db || db == "") {
+			return bindRecord
+		}
+	}
+	return nil
+}
+
+// GetAllBindRecord returns all bind records in cache.
+func (h *BindHandle) GetAllBindRecord() (bindRecords []*BindRecord) {
+	cache := h.bindInfo.Value.Load().(map[string][]*BindRecord)
+	for _, bindRecord := range cache {
+		bindRecords = append(bindRecords, bindRecord...)
+	}
+	return bindRecords
+}
+
+// GetBindRecordsForTable returns every BindRecord that references the given
+// schema-qualified table. It is the seam for `SHOW BINDINGS FOR TABLE
+// db.tbl`: the grammar and executor wiring for that statement live in the
+// parser/executor packages, which this tree doesn't contain, so that SQL
+// surface isn't reachable yet. This method is what its executor handler
+// would call once added.
+func (h *BindHandle) GetBindRecordsForTable(table string) []*BindRecord {
+	tableIdx := h.tableIndex.Value.Load().(map[string]map[*BindRecord]struct{})
+	set, ok := tableIdx[table]
+	if !ok {
+		return nil
+	}
+	records := make([]*BindRecord, 0, len(set))
+	for br := range set {
+		records = append(records, br)
+	}
+	return records
+}
+
+// DropBindRecordsForTable drops all bindings that reference the given
+// schema-qualified table. It is the seam for `DROP BINDINGS FOR TABLE
+// db.tbl`; invalidation after a table is dropped or renamed is done by
+// DropInvalidBindRecord, which does not call it. Like GetBindRecordsForTable, the statement itself isn't parseable
+// until the grammar/executor packages (absent from this tree) grow a
+// handler that calls this method.
+func (h *BindHandle) DropBindRecordsForTable(table string) error {
+	records := h.GetBindRecordsForTable(table)
+	for _, br := range records {
+		toDrop := &BindRecord{OriginalSQL: br.OriginalSQL, Db: br.Db}
+		for _, b := range br.Bindings {
+			dropped := b.clone()
+			dropped.Status = deleted
+			toDrop.Bindings = append(toDrop.Bindings, dropped)
+		}
+		if err := h.AddBindRecord(h.sctx.Context, toDrop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropBindRecord drops a single global binding identified by its BindSQL, or
+// every global binding for originalSQL/db when binding is nil, for `DROP
+// GLOBAL BINDING FOR <sql> [USING ...]`. It mirrors
+// SessionHandle.DropBindRecord for the global scope.
+func (h *BindHandle) DropBindRecord(originalSQL, db string, binding *Binding) error {
+	record := h.GetBindRecord(parser.DigestNormalized(originalSQL), originalSQL, db)
+	if record == nil {
+		return nil
+	}
+	toDrop := &BindRecord{OriginalSQL: originalSQL, Db: db}
+	for _, b := range record.Bindings {
+		if binding != nil && b.BindSQL != binding.BindSQL {
+			continue
+		}
+		dropped := b.clone()
+		dropped.Status = deleted
+		toDrop.Bindings = append(toDrop.Bindings, dropped)
+	}
+	if len(toDrop.Bindings) == 0 {
+		return nil
+	}
+	return h.AddBindRecord(h.sctx.Context, toDrop)
+}
+
+// Size returns the size of bind info cache.
+func (h *BindHandle) Size() int {
+	size := 0
+	for _, bindRecords := range h.bindInfo.Value.Load().(map[string][]*BindRecord) {
+		size += len(bindRecords)
+	}
+	return size
+}
+
+// Clear resets the bind handle cache. It is only used for test.
+func (h *BindHandle) Clear() {
+	h.bindInfo.Value.Store(make(map[string][]*BindRecord))
+	h.tableIndex.Value.Store(make(map[string]map[*BindRecord]struct{}))
+}
+
+func (h *BindHandle) lastUpdateTime() types.Time {
+	bindRecords := h.GetAllBindRecord()
+	maxUpdateTime := types.ZeroTime
+	for _, bindRecord := range bindRecords {
+		for _, binding := range bindRecord.Bindings {
+			if binding.UpdateTime.Compare(maxUpdateTime) > 0 {
+				maxUpdateTime = binding.UpdateTime
+			}
+		}
+	}
+	return maxUpdateTime
+}
+
+// DropInvalidBindRecord scans the cache for bindings whose referenced table
+// no longer exists, or whose hinted index no longer exists on a table that
+// is still there, and marks just those bindings invalid so stale bindings
+// don't linger after a DDL like `DROP TABLE`/`DROP INDEX`/`RENAME TABLE`.
+func (h *BindHandle) DropInvalidBindRecord() {
+	is := h.sctx.Context.GetInfoSchema().(infoschema.InfoSchema)
+	for _, record := range h.GetAllBindRecord() {
+		toDrop := &BindRecord{OriginalSQL: record.OriginalSQL, Db: record.Db}
+		for _, b := range record.Bindings {
+			if b.Status == deleted || b.Status == invalid || h.bindingValid(is, record, b) {
+				continue
+			}
+			invalidated := b.clone()
+			invalidated.Status = invalid
+			toDrop.Bindings = append(toDrop.Bindings, invalidated)
+		}
+		if len(toDrop.Bindings) == 0 {
+			continue
+		}
+		if err := h.AddBindRecord(h.sctx.Context, toDrop); err != nil {
+			continue
+		}
+	}
+}
+
+// bindingValid reports whether every table b's BindSQL references still
+// exists in is, and every index it hints via USE/FORCE/IGNORE INDEX still
+// exists on that table. A dropped or renamed table invalidates the binding
+// outright; a dropped or renamed index invalidates it even though the
+// table itself is still there.
+func (h *BindHandle) bindingValid(is infoschema.InfoSchema, record *BindRecord, b Binding) bool {
+	for _, t := range b.Tables {
+		db, tbl := splitTableName(t)
+		if db == "" {
+			continue
+		}
+		if _, err := is.TableByName(model.NewCIStr(db), model.NewCIStr(tbl)); err != nil {
+			return false
+		}
+	}
+	stmt, err := h.parser.ParseOneStmt(b.BindSQL, b.Charset, b.Collation)
+	if err != nil {
+		return true
+	}
+	for _, ref := range indexHintsFromNode(stmt, record.Db) {
+		if ref.db == "" {
+			continue
+		}
+		tbl, err := is.TableByName(model.NewCIStr(ref.db), model.NewCIStr(ref.tbl))
+		if err != nil {
+			return false
+		}
+		found := false
+		for _, idx := range tbl.Meta().Indices {
+			if idx.Name.L == ref.idx {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CaptureBaselines is used to automatically capture plan baselines.
+func (h *BindHandle) CaptureBaselines() {
+	// Captured SQL statements come from the statement summary table, already
+	// required to be enabled by tidb_capture_plan_baselines. Hooking that up
+	// lives in the executor package; bindinfo only owns persisting the
+	// resulting bindings, so this is intentionally left for the `admin
+	// capture bindings` executor to drive via AddBindRecord.
+}
+
+func (h *BindHandle) copyBindCache() map[string][]*BindRecord {
+	newCache := make(map[string][]*BindRecord)
+	old := h.bindInfo.Value.Load().(map[string][]*BindRecord)
+	for k, v := range old {
+		newCache[k] = append([]*BindRecord(nil), v...)
+	}
+	return newCache
+}
+
+func (h *BindHandle) copyTableIndex() map[string]map[*BindRecord]struct{} {
+	newIdx := make(map[string]map[*BindRecord]struct{})
+	old := h.tableIndex.Value.Load().(map[string]map[*BindRecord]struct{})
+	for k, v := range old {
+		set := make(map[*BindRecord]struct{}, len(v))
+		for br := range v {
+			set[br] = struct{}{}
+		}
+		newIdx[k] = set
+	}
+	return newIdx
+}
+
+func (h *BindHandle) getBindRecordLocked(cache map[string][]*BindRecord, originalSQL, db string) *BindRecord {
+	hash := parser.DigestNormalized(originalSQL)
+	for _, bindRecord := range cache[hash] {
+		if bindRecord.OriginalSQL == originalSQL && bindRecord.Db == db {
+			return bindRecord
+		}
+	}
+	return nil
+}
+
+// merge combines an existing record with a freshly-read one. A binding in
+// new replaces the binding with the same BindSQL in old whatever their
+// update times; deleted and invalid bindings are then dropped from the
+// result.
+func merge(old, new *BindRecord) *BindRecord {
+	var merged *BindRecord
+	if old == nil {
+		merged = &BindRecord{OriginalSQL: new.OriginalSQL, Db: new.Db}
+	} else {
+		merged = old.copy()
+	}
+	for _, b := range new.Bindings {
+		if existing := merged.FindBinding(b.BindSQL); existing != nil {
+			*existing = b
+		} else {
+			merged.Bindings = append(merged.Bindings, b)
+		}
+	}
+	live := merged.Bindings[:0]
+	for _, b := range merged.Bindings {
+		if b.Status != deleted && b.Status != invalid {
+			live = append(live, b)
+		}
+	}
+	merged.Bindings = live
+	return merged
+}
+
+func setBindRecord(cache map[string][]*BindRecord, record *BindRecord) {
+	hash := parser.DigestNormalized(record.OriginalSQL)
+	records := cache[hash]
+	for i, r := range records {
+		if r.isSame(record) {
+			records[i] = record
+			return
+		}
+	}
+	cache[hash] = append(records, record)
+}
+
+func removeBindRecord(cache map[string][]*BindRecord, record *BindRecord) {
+	hash := parser.DigestNormalized(record.OriginalSQL)
+	records := cache[hash]
+	for i, r := range records {
+		if r.isSame(record) {
+			cache[hash] = append(records[:i], records[i+1:]...)
+			return
+		}
+	}
+}
+
+func reindexBindRecord(tableIdx map[string]map[*BindRecord]struct{}, old, new *BindRecord) {
+	if old != nil {
+		for _, t := range old.Tables() {
+			if set, ok := tableIdx[t]; ok {
+				delete(set, old)
+			}
+		}
+	}
+	if new == nil || len(new.Bindings) == 0 {
+		return
+	}
+	for _, t := range new.Tables() {
+		set, ok := tableIdx[t]
+		if !ok {
+			set = make(map[*BindRecord]struct{})
+			tableIdx[t] = set
+		}
+		set[new] = struct{}{}
+	}
+}
+
+// updateBindMetrics adjusts the global-scope bind gauges by the delta in
+// "using" bindings (count and memory size) between old and new.
+func updateBindMetrics(old, new *BindRecord) {
+	oldCount, oldSize := usingStats(old)
+	newCount, newSize := usingStats(new)
+	if delta := newCount - oldCount; delta != 0 {
+		metrics.BindTotalGauge.WithLabelValues(metrics.ScopeGlobal, Using).Add(float64(delta))
+	}
+	if delta := newSize - oldSize; delta != 0 {
+		metrics.BindMemoryUsage.WithLabelValues(metrics.ScopeGlobal, Using).Add(delta)
+	}
+}
+
+func usingStats(br *BindRecord) (count int, size float64) {
+	if br == nil {
+		return 0, 0
+	}
+	for _, b := range br.Bindings {
+		if b.Status != Using {
+			continue
+		}
+		count++
+		size += bindingSize(br, b)
+	}
+	return count, size
+}
+
+func bindingSize(br *BindRecord, b Binding) float64 {
+	res := len(br.OriginalSQL) + len(br.Db) + len(b.BindSQL) + len(b.Status) + len(b.Charset) + len(b.Collation)
+	for _, t := range b.Tables {
+		res += len(t)
+	}
+	return float64(res)
+}
+
+func quoteSQL(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func splitTableName(table string) (db, tbl string) {
+	for i := 0; i < len(table); i++ {
+		if table[i] == '.' {
+			return table[:i], table[i+1:]
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,277 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/metrics"
+)
+
+// regressionThreshold is the fraction by which a query's sampled p95 latency
+// must exceed its recorded baseline before it is considered regressed.
+const regressionThreshold = 0.5
+
+// latencyWindow is the number of recent samples kept per sql digest to
+// compute a rolling p95.
+const latencyWindow = 64
+
+// warmupSamples is how many executions of a freshly-verified binding (one
+// with no baseline recorded yet) are observed before the rolling p95 of
+// those samples is trusted to seed the baseline. Without this, a single unlucky slow
+// execution would permanently pin a high baseline and suppress regression
+// detection for the rest of the binding's life.
+const warmupSamples = 5
+
+// EvolveTask describes a binding queued for re-evolution because its live
+// plan drifted away from the latency recorded when the binding was last
+// verified.
+type EvolveTask struct {
+	SQLDigest   string
+	OriginalSQL string
+	Db          string
+	BindSQL     string
+	Scope       string
+	BaselineNS  int64
+	SampledP95  int64
+	QueuedAt    time.Time
+}
+
+// regressionSampler tracks recent execution latencies for queries that are
+// using a binding, so BindHandle can notice when a live plan regresses
+// against the latency recorded at binding-evolution time.
+type regressionSampler struct {
+	mu sync.Mutex
+	// samples holds, per sql digest, a small ring buffer of recent latencies.
+	samples map[string][]int64
+	// baseline holds the last latency recorded as "using" for a sql digest.
+	baseline map[string]int64
+	next     map[string]int
+}
+
+func newRegressionSampler() *regressionSampler {
+	return &regressionSampler{
+		samples:  make(map[string][]int64),
+		baseline: make(map[string]int64),
+		next:     make(map[string]int),
+	}
+}
+
+// record stores a new latency sample (in nanoseconds) for sqlDigest, coming
+// from the statement summary that tidb_capture_plan_baselines already
+// requires to be enabled.
+func (s *regressionSampler) record(sqlDigest string, latencyNS int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf, ok := s.samples[sqlDigest]
+	if !ok {
+		buf = make([]int64, 0, latencyWindow)
+	}
+	if len(buf) < latencyWindow {
+		buf = append(buf, latencyNS)
+	} else {
+		buf[s.next[sqlDigest]] = latencyNS
+	}
+	s.next[sqlDigest] = (s.next[sqlDigest] + 1) % latencyWindow
+	s.samples[sqlDigest] = buf
+}
+
+// p95 returns the rolling p95 latency for sqlDigest, or 0 if it has no
+// samples yet.
+func (s *regressionSampler) p95(sqlDigest string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := s.samples[sqlDigest]
+	if len(buf) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), buf...)
+	sort64(sorted)
+	idx := (len(sorted) * 95) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func (s *regressionSampler) setBaseline(sqlDigest string, latencyNS int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseline[sqlDigest] = latencyNS
+}
+
+func (s *regressionSampler) getBaseline(sqlDigest string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.baseline[sqlDigest]
+	return v, ok
+}
+
+// sampleCount returns how many samples are currently held for sqlDigest.
+func (s *regressionSampler) sampleCount(sqlDigest string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.samples[sqlDigest])
+}
+
+// resetBaseline clears a sql digest's baseline and accumulated samples, so
+// whatever arrives next has to re-establish it from scratch. Called
+// whenever a binding for that digest is (re-)verified, so the baseline
+// always reflects the binding's current verified epoch instead of a value
+// carried over from before the binding changed.
+func (s *regressionSampler) resetBaseline(sqlDigest string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.baseline, sqlDigest)
+	delete(s.samples, sqlDigest)
+	delete(s.next, sqlDigest)
+}
+
+func sort64(s []int64) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && s[j-1] > s[j]; j-- {
+			s[j-1], s[j] = s[j], s[j-1]
+		}
+	}
+}
+
+// RecordExecution feeds one query's observed latency into the regression
+// sampler and, if the binding in use for it has drifted more than
+// regressionThreshold slower than its recorded baseline, enqueues a
+// re-evolution task and demotes the binding to "pending verify".
+//
+// Like CaptureBaselines, bindinfo only owns the sampling and the resulting
+// status transition; it does not drive itself. The caller is meant to be
+// the executor's statement-finish path (the same place that already feeds
+// the statement summary for tidb_capture_plan_baselines), invoking this
+// once per finished statement that used a global binding. Wiring that call
+// site lives in the executor package and is out of scope here.
+func (h *BindHandle) RecordExecution(db, normdOrigSQL, sqlDigest string, latencyNS int64) {
+	h.sampler.record(sqlDigest, latencyNS)
+
+	record := h.GetBindRecord(sqlDigest, normdOrigSQL, db)
+	if record == nil || !record.HasUsingBinding() {
+		return
+	}
+	baseline, ok := h.sampler.getBaseline(sqlDigest)
+	if !ok {
+		// No verified baseline yet for this digest: wait for a short warm-up
+		// window rather than trusting whatever single sample happens to
+		// arrive first, then seed the baseline from its rolling p95.
+		if h.sampler.sampleCount(sqlDigest) < warmupSamples {
+			return
+		}
+		h.sampler.setBaseline(sqlDigest, h.sampler.p95(sqlDigest))
+		return
+	}
+	p95 := h.sampler.p95(sqlDigest)
+	if baseline == 0 || p95 == 0 {
+		return
+	}
+	if float64(p95-baseline)/float64(baseline) <= regressionThreshold {
+		return
+	}
+
+	using := record.Bindings[0]
+	for _, b := range record.Bindings {
+		if b.Status == Using {
+			using = b
+			break
+		}
+	}
+	scope := metrics.ScopeGlobal
+	metrics.BindRegressionCounter.WithLabelValues(sqlDigest, scope).Inc()
+
+	demoted := using.clone()
+	demoted.Status = PendingVerify
+	if err := h.AddBindRecord(h.sctx.Context, &BindRecord{
+		OriginalSQL: record.OriginalSQL,
+		Db:          record.Db,
+		Bindings:    []Binding{demoted},
+	}); err != nil {
+		return
+	}
+
+	h.evolveTasks.Lock()
+	h.evolveTasks.tasks = append(h.evolveTasks.tasks, EvolveTask{
+		SQLDigest:   sqlDigest,
+		OriginalSQL: record.OriginalSQL,
+		Db:          record.Db,
+		BindSQL:     using.BindSQL,
+		Scope:       scope,
+		BaselineNS:  baseline,
+		SampledP95:  p95,
+		QueuedAt:    nowTime(),
+	})
+	h.evolveTasks.Unlock()
+}
+
+// GetEvolveTasks returns the re-evolution tasks enqueued so far by
+// regression detection, for `admin show evolve tasks`.
+func (h *BindHandle) GetEvolveTasks() []EvolveTask {
+	h.evolveTasks.Lock()
+	defer h.evolveTasks.Unlock()
+	tasks := make([]EvolveTask, len(h.evolveTasks.tasks))
+	copy(tasks, h.evolveTasks.tasks)
+	return tasks
+}
+
+// CompleteEvolveTask marks an evolve task as verified: it re-baselines the
+// task's sql digest against verifiedLatencyNS (the latency observed while
+// re-running the candidate binding) and transitions that binding back to
+// "using", so the baseline regression detection compares against always
+// reflects the latency recorded the last time the binding was actually
+// verified, never just whatever sample happened to arrive first. It's meant
+// to be driven by the same `admin evolve bindings` path that already
+// produces EvolveTasks via GetEvolveTasks.
+func (h *BindHandle) CompleteEvolveTask(task EvolveTask, verifiedLatencyNS int64) error {
+	record := h.GetBindRecord(task.SQLDigest, task.OriginalSQL, task.Db)
+	if record == nil {
+		return errors.New("bindinfo: no such binding to complete the evolve task for")
+	}
+	existing := record.FindBinding(task.BindSQL)
+	if existing == nil {
+		return errors.New("bindinfo: no such binding to complete the evolve task for")
+	}
+
+	verified := existing.clone()
+	verified.Status = Using
+	if err := h.AddBindRecord(h.sctx.Context, &BindRecord{
+		OriginalSQL: task.OriginalSQL,
+		Db:          task.Db,
+		Bindings:    []Binding{verified},
+	}); err != nil {
+		return err
+	}
+	h.sampler.setBaseline(task.SQLDigest, verifiedLatencyNS)
+	h.removeEvolveTask(task)
+	return nil
+}
+
+// removeEvolveTask drops task from the queue once CompleteEvolveTask has
+// verified it.
+func (h *BindHandle) removeEvolveTask(task EvolveTask) {
+	h.evolveTasks.Lock()
+	defer h.evolveTasks.Unlock()
+	tasks := h.evolveTasks.tasks[:0]
+	for _, t := range h.evolveTasks.tasks {
+		if t.SQLDigest == task.SQLDigest && t.BindSQL == task.BindSQL {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	h.evolveTasks.tasks = tasks
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bindinfo
+
+import (
+	"github.com/pingcap/parser"
+	"github.com/pingcap/tidb/metrics"
+)
+
+type sessionBindInfoKeyType int
+
+// SessionBindInfoKeyType is a variable key for store session bind info.
+const SessionBindInfoKeyType sessionBindInfoKeyType = 0
+
+// SessionHandle is used to handle all session sql bind operations.
+type SessionHandle struct {
+	ch map[string]*BindRecord
+}
+
+// NewSessionBindHandle creates a new SessionBindHandle.
+func NewSessionBindHandle(parser *parser.Parser) *SessionHandle {
+	sessionHandle := &SessionHandle{}
+	sessionHandle.ch = make(map[string]*BindRecord)
+	return sessionHandle
+}
+
+// appendBindRecord adds the BindRecord to the cache, all the stale
+// BindRecords are removed from the cache after this operation.
+func (h *SessionHandle) appendBindRecord(hash string, meta *BindRecord) {
+	oldRecord := h.ch[hash]
+	newRecord := merge(oldRecord, meta)
+	h.ch[hash] = newRecord
+
+	oldCount, oldSize := usingStats(oldRecord)
+	newCount, newSize := usingStats(newRecord)
+	if delta := newCount - oldCount; delta != 0 {
+		metrics.BindTotalGauge.WithLabelValues(metrics.ScopeSession, Using).Add(float64(delta))
+	}
+	if delta := newSize - oldSize; delta != 0 {
+		metrics.BindMemoryUsage.WithLabelValues(metrics.ScopeSession, Using).Add(delta)
+	}
+}
+
+// AddBindRecord new a BindRecord with BindMeta, add it to the cache.
+func (h *SessionHandle) AddBindRecord(record *BindRecord) {
+	h.appendBindRecord(parser.DigestNormalized(record.OriginalSQL), record)
+}
+
+// DropBindRecord drops a BindRecord in the cache.
+func (h *SessionHandle) DropBindRecord(originalSQL, db string, binding *Binding) error {
+	record := &BindRecord{OriginalSQL: originalSQL, Db: db}
+	if binding != nil {
+		record.Bindings = append(record.Bindings, *binding)
+	} else if old := h.GetBindRecord(originalSQL, db); old != nil {
+		for _, b := range old.Bindings {
+			record.Bindings = append(record.Bindings, b.clone())
+		}
+	}
+	for i := range record.Bindings {
+		record.Bindings[i].Status = deleted
+	}
+	h.appendBindRecord(parser.DigestNormalized(originalSQL), record)
+	return nil
+}
+
+// GetBindRecord return the BindRecord of the (normdOrigSQL,db) if BindRecord exist.
+func (h *SessionHandle) GetBindRecord(normdOrigSQL, db string) *BindRecord {
+	hash := parser.DigestNormalized(normdOrigSQL)
+	bindRecord, ok := h.ch[hash]
+	if !ok {
+		return nil
+	}
+	if bindRecord.OriginalSQL != normdOrigSQL || bindRecord.Db != db {
+		return nil
+	}
+	return bindRecord
+}
+
+// GetAllBindRecord return all BindRecord in the SessionHandle.
+func (h *SessionHandle) GetAllBindRecord() (bindRecords []*BindRecord) {
+	for _, bindRecord := range h.ch {
+		bindRecords = append(bindRecords, bindRecord)
+	}
+	return bindRecords
+}
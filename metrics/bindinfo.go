@@ -0,0 +1,63 @@
+// Copyright 2019 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Scope constants used to label bindinfo metrics.
+const (
+	ScopeGlobal  = "global"
+	ScopeSession = "session"
+)
+
+// Metrics for the bindinfo package.
+var (
+	// BindTotalGauge records the number of each status of bindings.
+	BindTotalGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "bind_total",
+			Help:      "Total number of bindings by scope and status.",
+		}, []string{"scope", "type"})
+
+	// BindMemoryUsage records the memory usage of each status of bindings.
+	BindMemoryUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "bind_memory_usage",
+			Help:      "Memory usage of bindings by scope and status.",
+		}, []string{"scope", "type"})
+
+	// BindUsageCounter records the usage count of bindings by scope.
+	BindUsageCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "bind_usage_total",
+			Help:      "Counter of bindings used when planning a query, by scope.",
+		}, []string{"scope"})
+
+	// BindRegressionCounter records how many times a query using a binding
+	// was found to have regressed against its recorded baseline latency,
+	// triggering automatic re-evolution.
+	BindRegressionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "bind_regression_total",
+			Help:      "Counter of detected plan regressions for bindings in use, by sql digest and scope.",
+		}, []string{"sql_digest", "scope"})
+)